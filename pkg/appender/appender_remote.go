@@ -11,10 +11,12 @@ import (
 	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
 	"github.com/google/go-containerregistry/pkg/v1/mutate"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/go-containerregistry/pkg/v1/types"
 	specsv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/turbokube/contain/pkg/basecache"
 	schema "github.com/turbokube/contain/pkg/schema/v1"
 	"go.uber.org/zap"
 )
@@ -27,6 +29,8 @@ type Appender struct {
 	config       schema.ContainConfig
 	baseRef      name.Reference
 	tagRef       name.Reference
+	output       Output
+	baseCache    *basecache.Cache
 	mediaType    types.MediaType
 	layerType    types.MediaType
 	craneOptions crane.Options
@@ -44,12 +48,38 @@ func New(config schema.ContainConfig) (*Appender, error) {
 	}
 	zap.L().Debug("base image", zap.String("ref", c.baseRef.String()))
 
-	c.tagRef, err = name.ParseReference(config.Tag)
-	if err != nil {
-		zap.L().Error("Failed to parse result image ref", zap.String("ref", config.Tag), zap.Error(err))
+	if config.Cache.Path != "" {
+		c.baseCache, err = basecache.New(config.Cache, c.craneOptions)
+		if err != nil {
+			zap.L().Error("Failed to open base cache", zap.String("path", config.Cache.Path), zap.Error(err))
+		}
 	}
-	if c.tagRef != nil {
-		zap.L().Debug("target image", zap.String("ref", c.tagRef.String()))
+
+	scheme, rest := splitOutputScheme(config.Tag)
+	switch scheme {
+	case outputSchemeOCILayout:
+		zap.L().Debug("target OCI layout", zap.String("path", rest))
+		c.output = &ociLayoutOutput{path: rest}
+	case outputSchemeTarball:
+		zap.L().Debug("target tarball", zap.String("path", rest))
+		c.output = &tarballOutput{path: rest}
+	case outputSchemeDaemon:
+		tag, tagErr := name.NewTag(rest)
+		if tagErr != nil {
+			return nil, fmt.Errorf("daemon output requires a tag reference, got %q: %w", rest, tagErr)
+		}
+		c.tagRef = tag
+		zap.L().Debug("target daemon tag", zap.String("ref", tag.String()))
+		c.output = &daemonOutput{ref: tag}
+	default:
+		c.tagRef, err = name.ParseReference(rest)
+		if err != nil {
+			zap.L().Error("Failed to parse result image ref", zap.String("ref", config.Tag), zap.Error(err))
+		}
+		if c.tagRef != nil {
+			zap.L().Debug("target image", zap.String("ref", c.tagRef.String()))
+		}
+		c.output = &registryOutput{ref: c.tagRef, craneOptions: c.craneOptions}
 	}
 
 	return &c, nil
@@ -70,9 +100,18 @@ func (c *Appender) base() (v1.Image, error) {
 	var err error
 	var mediaType = types.OCIManifestSchema1
 
-	base, err = remote.Image(c.baseRef, c.craneOptions.Remote...)
-	if err != nil {
-		return nil, fmt.Errorf("pulling %s: %w", c.baseRef.String(), err)
+	if c.baseCache != nil {
+		var digest v1.Hash
+		base, digest, err = c.baseCache.Get(c.baseRef)
+		if err != nil {
+			return nil, fmt.Errorf("pulling %s via cache: %w", c.baseRef.String(), err)
+		}
+		c.config.Status.ResolvedBaseDigest = digest.String()
+	} else {
+		base, err = remote.Image(c.baseRef, c.craneOptions.Remote...)
+		if err != nil {
+			return nil, fmt.Errorf("pulling %s: %w", c.baseRef.String(), err)
+		}
 	}
 	mediaType, err = base.MediaType()
 	if err != nil {
@@ -92,20 +131,35 @@ func (c *Appender) base() (v1.Image, error) {
 
 // Append is what you call once layers are ready
 func (c *Appender) Append(layers ...v1.Layer) (v1.Hash, error) {
-	// Platform support remains to be verified with for example docker hub
-	// See also https://github.com/google/go-containerregistry/issues/1456 and https://github.com/google/go-containerregistry/pull/1561
-	if len(c.config.Platforms) > 1 {
-		zap.L().Warn("unsupported multiple platforms, falling back to all", zap.Strings("platforms", c.config.Platforms))
+	noresult := v1.Hash{}
+	buildTimestamp := time.Now()
+	created := c.createdTime(buildTimestamp, layers)
+
+	layers, err := c.normalizeLayers(layers, created)
+	if err != nil {
+		zap.L().Error("Failed to normalize layers for reproducible build", zap.Error(err))
+		return noresult, err
 	}
-	if len(c.config.Platforms) == 1 {
-		zap.L().Warn("unsupported single platform, falling back to all", zap.String("platform", c.config.Platforms[0]))
+
+	isIndex, err := c.baseIsIndex()
+	if err != nil {
+		zap.L().Error("Failed to probe base image", zap.Error(err))
+		return noresult, err
 	}
-	noresult := v1.Hash{}
+	if isIndex {
+		return c.appendIndex(created, layers...)
+	}
+
+	if len(c.config.Platforms) > 0 {
+		zap.L().Warn("base is a single-platform image, ignoring requested platforms", zap.Strings("platforms", c.config.Platforms))
+	}
+
 	base, err := c.base()
 	if err != nil {
 		zap.L().Error("Failed to get base image", zap.Error(err))
 		return noresult, err
 	}
+	warnIfBaseNotReproducible(base)
 	baseDigest, err := base.Digest()
 	if err != nil {
 		zap.L().Error("Failed to get base image digest", zap.Error(err))
@@ -120,12 +174,17 @@ func (c *Appender) Append(layers ...v1.Layer) (v1.Hash, error) {
 		zap.L().Error("Failed to annotate", zap.Error(err))
 		return noresult, err
 	}
-	imgDigest, err := img.Digest()
+	img, err = c.withCreated(img, created)
 	if err != nil {
-		zap.L().Error("Failed to get result image digest", zap.Error(err))
+		zap.L().Error("Failed to set reproducible created timestamp", zap.Error(err))
 		return noresult, err
 	}
-	err = c.push(img)
+	img, err = c.withImageConfig(img)
+	if err != nil {
+		zap.L().Error("Failed to apply image config", zap.Error(err))
+		return noresult, err
+	}
+	imgDigest, err := c.output.Push(img)
 	if err != nil {
 		zap.L().Error("Failed to push", zap.Error(err))
 		return noresult, err
@@ -136,67 +195,175 @@ func (c *Appender) Append(layers ...v1.Layer) (v1.Hash, error) {
 	return imgDigest, nil
 }
 
-// annotate is called after append
-func (c *Appender) annotate(image v1.Image, baseDigest v1.Hash) v1.Image {
-	// https://github.com/google/go-containerregistry/blob/v0.13.0/cmd/crane/cmd/append.go#L71
-	a := map[string]string{
-		specsv1.AnnotationBaseImageDigest: baseDigest.String(),
-	}
-	if _, ok := c.baseRef.(name.Tag); ok {
-		a[specsv1.AnnotationBaseImageName] = fmt.Sprintf("/%s:%s",
-			c.baseRef.Context().RepositoryStr(),
-			c.baseRef.Identifier(),
-		)
+// baseIsIndex reports whether baseRef resolves to an image index/manifest list,
+// without pulling the full image(s).
+func (c *Appender) baseIsIndex() (bool, error) {
+	desc, err := remote.Get(c.baseRef, c.craneOptions.Remote...)
+	if err != nil {
+		return false, fmt.Errorf("getting %s: %w", c.baseRef.String(), err)
 	}
-	img := mutate.Annotations(image, a).(v1.Image)
-	return img
+	return desc.MediaType.IsIndex(), nil
 }
 
-func (c *Appender) push(image v1.Image) error {
-	mediaType, err := image.MediaType()
+// appendIndex handles the case where baseRef is an image index: it appends layers
+// to each platform-specific image matching ContainConfig.Platforms and rebuilds an
+// index from the results.
+func (c *Appender) appendIndex(created time.Time, layers ...v1.Layer) (v1.Hash, error) {
+	noresult := v1.Hash{}
+
+	if len(c.config.Platforms) == 0 {
+		zap.L().Warn("base is a manifest list but no platforms configured, pushing all platforms found")
+	}
+
+	wanted, err := parsePlatforms(c.config.Platforms)
 	if err != nil {
-		return err
+		zap.L().Error("Failed to parse platforms", zap.Error(err))
+		return noresult, err
 	}
-	zap.L().Info("pushing", zap.String("mediaType", string(mediaType)))
 
-	debounce, err := time.ParseDuration(progressReportMinInterval)
+	if c.baseCache != nil {
+		zap.L().Warn("base image is a manifest list, base caching is not supported for multi-platform builds and will be skipped", zap.String("ref", c.baseRef.String()))
+	}
+
+	baseIndex, err := remote.Index(c.baseRef, c.craneOptions.Remote...)
 	if err != nil {
-		zap.L().Fatal("failed to parse debounce interval", zap.String("value", progressReportMinInterval), zap.Error(err))
+		return noresult, fmt.Errorf("pulling index %s: %w", c.baseRef.String(), err)
+	}
+	baseManifest, err := baseIndex.IndexManifest()
+	if err != nil {
+		return noresult, fmt.Errorf("reading index manifest %s: %w", c.baseRef.String(), err)
+	}
+	if indexDigest, err := baseIndex.Digest(); err == nil {
+		c.config.Status.ResolvedBaseDigest = indexDigest.String()
+	}
+	c.mediaType = baseManifest.MediaType
+	if baseManifest.MediaType == types.OCIImageIndex {
+		c.layerType = types.OCILayer
+	} else {
+		c.layerType = types.DockerLayer
 	}
 
-	progressChan := make(chan v1.Update, 200)
-	errChan := make(chan error, 2)
+	adds := []mutate.IndexAddendum{}
+	for _, desc := range baseManifest.Manifests {
+		if desc.Platform == nil {
+			continue
+		}
+		if len(wanted) > 0 && !platformWanted(*desc.Platform, wanted) {
+			continue
+		}
 
-	go func() {
-		options := append(c.craneOptions.Remote, remote.WithProgress(progressChan))
-		errChan <- remote.Write(
-			c.tagRef,
-			image,
-			options...,
+		base, err := baseIndex.Image(desc.Digest)
+		if err != nil {
+			return noresult, fmt.Errorf("getting base image %s for platform %s: %w", desc.Digest, desc.Platform, err)
+		}
+		warnIfBaseNotReproducible(base)
+		img, err := mutate.AppendLayers(base, layers...)
+		if err != nil {
+			return noresult, fmt.Errorf("appending layers for platform %s: %w", desc.Platform, err)
+		}
+		img = c.annotate(img, desc.Digest)
+		img, err = c.withCreated(img, created)
+		if err != nil {
+			return noresult, fmt.Errorf("setting reproducible created timestamp for platform %s: %w", desc.Platform, err)
+		}
+		img, err = c.withImageConfig(img)
+		if err != nil {
+			return noresult, fmt.Errorf("applying image config for platform %s: %w", desc.Platform, err)
+		}
+
+		cfg, err := img.ConfigFile()
+		if err != nil {
+			return noresult, fmt.Errorf("reading config for platform %s: %w", desc.Platform, err)
+		}
+		platform := &v1.Platform{
+			Architecture: cfg.Architecture,
+			OS:           cfg.OS,
+			OSVersion:    cfg.OSVersion,
+			Variant:      cfg.Variant,
+		}
+
+		imgDigest, err := img.Digest()
+		if err != nil {
+			return noresult, fmt.Errorf("getting result digest for platform %s: %w", desc.Platform, err)
+		}
+		zap.L().Info("appended platform image",
+			zap.String("platform", desc.Platform.String()),
+			zap.String("digest", imgDigest.String()),
 		)
-	}()
 
-	logger := zap.L()
-	nextProgress := time.Now().Add(debounce)
+		adds = append(adds, mutate.IndexAddendum{
+			Add: img,
+			Descriptor: v1.Descriptor{
+				Platform: platform,
+			},
+		})
+	}
+
+	if len(adds) == 0 {
+		return noresult, fmt.Errorf("no manifests in %s matched requested platforms %v", c.baseRef.String(), c.config.Platforms)
+	}
+
+	outIndex := mutate.AppendManifests(empty.Index, adds...)
+	outIndex = mutate.IndexMediaType(outIndex, baseManifest.MediaType)
+
+	indexDigest, err := c.output.Push(outIndex)
+	if err != nil {
+		zap.L().Error("Failed to push index", zap.Error(err))
+		return noresult, err
+	}
+	zap.L().Info("pushed index",
+		zap.String("digest", indexDigest.String()),
+		zap.Int("platforms", len(adds)),
+	)
+	return indexDigest, nil
+}
 
-	for update := range progressChan {
-		if update.Error != nil {
-			logger.Error("push update", zap.Error(update.Error))
-			errChan <- update.Error
-			break
+// parsePlatforms parses ContainConfig.Platforms entries such as "linux/amd64" or
+// "linux/arm64/v8" into v1.Platform values to match against index manifests.
+func parsePlatforms(platforms []string) ([]v1.Platform, error) {
+	parsed := make([]v1.Platform, 0, len(platforms))
+	for _, p := range platforms {
+		platform, err := v1.ParsePlatform(p)
+		if err != nil {
+			return nil, fmt.Errorf("parsing platform %q: %w", p, err)
+		}
+		if platform.OS == "" || platform.Architecture == "" {
+			return nil, fmt.Errorf("parsing platform %q: want os/arch[/variant]", p)
 		}
+		parsed = append(parsed, *platform)
+	}
+	return parsed, nil
+}
 
-		if update.Complete == update.Total {
-			logger.Info("pushed", zap.Int64("completed", update.Complete), zap.Int64("total", update.Total))
-		} else {
-			if time.Now().After(nextProgress) {
-				nextProgress = time.Now().Add(debounce)
-				logger.Info("push", zap.Int64("completed", update.Complete), zap.Int64("total", update.Total))
-			}
+// platformWanted reports whether platform matches one of the wanted platforms on
+// OS, Architecture and, if specified, Variant.
+func platformWanted(platform v1.Platform, wanted []v1.Platform) bool {
+	for _, w := range wanted {
+		if w.OS != platform.OS || w.Architecture != platform.Architecture {
+			continue
+		}
+		if w.Variant != "" && w.Variant != platform.Variant {
+			continue
 		}
+		return true
 	}
+	return false
+}
 
-	return <-errChan
+// annotate is called after append
+func (c *Appender) annotate(image v1.Image, baseDigest v1.Hash) v1.Image {
+	// https://github.com/google/go-containerregistry/blob/v0.13.0/cmd/crane/cmd/append.go#L71
+	a := map[string]string{
+		specsv1.AnnotationBaseImageDigest: baseDigest.String(),
+	}
+	if _, ok := c.baseRef.(name.Tag); ok {
+		a[specsv1.AnnotationBaseImageName] = fmt.Sprintf("/%s:%s",
+			c.baseRef.Context().RepositoryStr(),
+			c.baseRef.Identifier(),
+		)
+	}
+	img := mutate.Annotations(image, a).(v1.Image)
+	return img
 }
 
 func (c *Appender) LayerType() types.MediaType {
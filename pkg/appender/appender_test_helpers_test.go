@@ -0,0 +1,14 @@
+package appender
+
+import schema "github.com/turbokube/contain/pkg/schema/v1"
+
+// testDigestHex is a syntactically valid sha256 hex digest for use in tests that
+// need a well-formed digest reference without resolving anything over the network.
+const testDigestHex = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func testConfig(base, tag string) schema.ContainConfig {
+	return schema.ContainConfig{
+		Base: base,
+		Tag:  tag,
+	}
+}
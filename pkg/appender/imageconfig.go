@@ -0,0 +1,109 @@
+package appender
+
+import (
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+)
+
+// withImageConfig applies ContainConfig.ImageConfig onto img, merging list and
+// map fields into the base image's config unless overridden per field group via
+// ImageConfig.Replace. A zero-value ImageConfig leaves img untouched.
+func (c *Appender) withImageConfig(img v1.Image) (v1.Image, error) {
+	ic := c.config.ImageConfig
+	if len(ic.Entrypoint) == 0 && len(ic.Cmd) == 0 && len(ic.Env) == 0 &&
+		ic.WorkingDir == "" && ic.User == "" && ic.StopSignal == "" &&
+		len(ic.ExposedPorts) == 0 && len(ic.Volumes) == 0 && len(ic.Labels) == 0 {
+		return img, nil
+	}
+
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	config := cfg.Config
+
+	if len(ic.Entrypoint) > 0 {
+		if ic.Replace.Entrypoint {
+			config.Entrypoint = ic.Entrypoint
+		} else {
+			config.Entrypoint = append(append([]string{}, config.Entrypoint...), ic.Entrypoint...)
+		}
+	}
+	if len(ic.Cmd) > 0 {
+		if ic.Replace.Cmd {
+			config.Cmd = ic.Cmd
+		} else {
+			config.Cmd = append(append([]string{}, config.Cmd...), ic.Cmd...)
+		}
+	}
+	if len(ic.Env) > 0 {
+		if ic.Replace.Env {
+			config.Env = ic.Env
+		} else {
+			config.Env = mergeEnv(config.Env, ic.Env)
+		}
+	}
+	if ic.WorkingDir != "" {
+		config.WorkingDir = ic.WorkingDir
+	}
+	if ic.User != "" {
+		config.User = ic.User
+	}
+	if ic.StopSignal != "" {
+		config.StopSignal = ic.StopSignal
+	}
+	if len(ic.ExposedPorts) > 0 {
+		if ic.Replace.ExposedPorts || config.ExposedPorts == nil {
+			config.ExposedPorts = map[string]struct{}{}
+		}
+		for _, p := range ic.ExposedPorts {
+			config.ExposedPorts[p] = struct{}{}
+		}
+	}
+	if len(ic.Volumes) > 0 {
+		if ic.Replace.Volumes || config.Volumes == nil {
+			config.Volumes = map[string]struct{}{}
+		}
+		for _, v := range ic.Volumes {
+			config.Volumes[v] = struct{}{}
+		}
+	}
+	if len(ic.Labels) > 0 {
+		if config.Labels == nil {
+			config.Labels = map[string]string{}
+		}
+		for k, v := range ic.Labels {
+			config.Labels[k] = v
+		}
+	}
+
+	return mutate.Config(img, config)
+}
+
+// mergeEnv overlays override entries ("KEY=VALUE") onto base, replacing the
+// value of keys that already exist and appending new keys.
+func mergeEnv(base, override []string) []string {
+	merged := append([]string{}, base...)
+	index := make(map[string]int, len(merged))
+	for i, kv := range merged {
+		if k, _, ok := strings.Cut(kv, "="); ok {
+			index[k] = i
+		}
+	}
+	for _, kv := range override {
+		k, _, ok := strings.Cut(kv, "=")
+		if !ok {
+			merged = append(merged, kv)
+			continue
+		}
+		if i, exists := index[k]; exists {
+			merged[i] = kv
+		} else {
+			index[k] = len(merged)
+			merged = append(merged, kv)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,132 @@
+package appender
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	schema "github.com/turbokube/contain/pkg/schema/v1"
+)
+
+func TestMergeEnvOverridesExistingAndAppendsNew(t *testing.T) {
+	base := []string{"PATH=/usr/bin", "DEBUG=0"}
+	override := []string{"DEBUG=1", "NEW=yes"}
+
+	got := mergeEnv(base, override)
+	want := []string{"PATH=/usr/bin", "DEBUG=1", "NEW=yes"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeEnv(%v, %v) = %v, want %v", base, override, got, want)
+	}
+}
+
+func TestMergeEnvLeavesBaseUntouched(t *testing.T) {
+	base := []string{"PATH=/usr/bin"}
+	_ = mergeEnv(base, []string{"PATH=/other"})
+	if base[0] != "PATH=/usr/bin" {
+		t.Errorf("mergeEnv mutated its base slice: %v", base)
+	}
+}
+
+func TestMergeEnvToleratesValuelessOverride(t *testing.T) {
+	got := mergeEnv([]string{"PATH=/usr/bin"}, []string{"JUST_A_FLAG"})
+	want := []string{"PATH=/usr/bin", "JUST_A_FLAG"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeEnv with a valueless override = %v, want %v", got, want)
+	}
+}
+
+func testImageWithConfig(t *testing.T, config v1.Config) v1.Image {
+	t.Helper()
+	img, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatalf("building random image: %v", err)
+	}
+	img, err = mutate.Config(img, config)
+	if err != nil {
+		t.Fatalf("setting base config: %v", err)
+	}
+	return img
+}
+
+func TestWithImageConfigAppendsEnvByDefault(t *testing.T) {
+	base := testImageWithConfig(t, v1.Config{Env: []string{"PATH=/usr/bin"}})
+	c := &Appender{config: schema.ContainConfig{
+		ImageConfig: schema.ImageConfig{Env: []string{"DEBUG=1"}},
+	}}
+
+	img, err := c.withImageConfig(base)
+	if err != nil {
+		t.Fatalf("withImageConfig: %v", err)
+	}
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		t.Fatalf("reading result config: %v", err)
+	}
+	want := []string{"PATH=/usr/bin", "DEBUG=1"}
+	if !reflect.DeepEqual(cfg.Config.Env, want) {
+		t.Errorf("Env = %v, want %v", cfg.Config.Env, want)
+	}
+}
+
+func TestWithImageConfigReplacesEnvWhenConfigured(t *testing.T) {
+	base := testImageWithConfig(t, v1.Config{Env: []string{"PATH=/usr/bin"}})
+	c := &Appender{config: schema.ContainConfig{
+		ImageConfig: schema.ImageConfig{
+			Env:     []string{"DEBUG=1"},
+			Replace: schema.ImageConfigReplace{Env: true},
+		},
+	}}
+
+	img, err := c.withImageConfig(base)
+	if err != nil {
+		t.Fatalf("withImageConfig: %v", err)
+	}
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		t.Fatalf("reading result config: %v", err)
+	}
+	want := []string{"DEBUG=1"}
+	if !reflect.DeepEqual(cfg.Config.Env, want) {
+		t.Errorf("Env = %v, want %v", cfg.Config.Env, want)
+	}
+}
+
+func TestWithImageConfigMergesLabels(t *testing.T) {
+	base := testImageWithConfig(t, v1.Config{Labels: map[string]string{"existing": "yes"}})
+	c := &Appender{config: schema.ContainConfig{
+		ImageConfig: schema.ImageConfig{Labels: map[string]string{"new": "yes"}},
+	}}
+
+	img, err := c.withImageConfig(base)
+	if err != nil {
+		t.Fatalf("withImageConfig: %v", err)
+	}
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		t.Fatalf("reading result config: %v", err)
+	}
+	want := map[string]string{"existing": "yes", "new": "yes"}
+	if !reflect.DeepEqual(cfg.Config.Labels, want) {
+		t.Errorf("Labels = %v, want %v", cfg.Config.Labels, want)
+	}
+}
+
+func TestWithImageConfigLeavesImageUntouchedWhenEmpty(t *testing.T) {
+	base := testImageWithConfig(t, v1.Config{Env: []string{"PATH=/usr/bin"}})
+	c := &Appender{config: schema.ContainConfig{}}
+
+	img, err := c.withImageConfig(base)
+	if err != nil {
+		t.Fatalf("withImageConfig: %v", err)
+	}
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		t.Fatalf("reading result config: %v", err)
+	}
+	want := []string{"PATH=/usr/bin"}
+	if !reflect.DeepEqual(cfg.Config.Env, want) {
+		t.Errorf("Env = %v, want %v unchanged", cfg.Config.Env, want)
+	}
+}
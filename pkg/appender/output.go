@@ -0,0 +1,79 @@
+package appender
+
+import (
+	"strings"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// outputSchemeDaemon side-loads the result into a local Docker daemon,
+	// for example "daemon://myapp:dev".
+	outputSchemeDaemon = "daemon://"
+	// outputSchemeOCILayout appends the result into an OCI layout directory on
+	// disk, for example "oci-layout:./out".
+	outputSchemeOCILayout = "oci-layout:"
+	// outputSchemeTarball writes the result as a single tarball for air-gapped
+	// delivery, for example "tarball:./img.tar".
+	outputSchemeTarball = "tarball:"
+)
+
+// Pushable is the subset of v1.Image and v1.ImageIndex that an Output needs to
+// push a result and report its digest.
+type Pushable interface {
+	MediaType() (types.MediaType, error)
+	Digest() (v1.Hash, error)
+	RawManifest() ([]byte, error)
+}
+
+// Output is a push destination for the image or image index produced by
+// Appender.Append. Implementations are selected from ContainConfig.Tag's scheme,
+// see splitOutputScheme.
+type Output interface {
+	Push(image Pushable) (v1.Hash, error)
+}
+
+// splitOutputScheme splits a ContainConfig.Tag value into an output scheme and
+// the remainder (a reference for daemon/registry, a path for oci-layout/tarball).
+// An empty scheme means the registry default.
+func splitOutputScheme(tag string) (scheme, rest string) {
+	for _, s := range []string{outputSchemeDaemon, outputSchemeOCILayout, outputSchemeTarball} {
+		if strings.HasPrefix(tag, s) {
+			return s, strings.TrimPrefix(tag, s)
+		}
+	}
+	return "", tag
+}
+
+// reportProgress drains progressChan, logging debounced progress, and returns
+// the push error once the underlying write completes. Only registryOutput uses
+// it; the daemon, OCI layout and tarball outputs report no progress.
+func reportProgress(progressChan <-chan v1.Update, errChan chan error) error {
+	debounce, err := time.ParseDuration(progressReportMinInterval)
+	if err != nil {
+		zap.L().Fatal("failed to parse debounce interval", zap.String("value", progressReportMinInterval), zap.Error(err))
+	}
+
+	logger := zap.L()
+	nextProgress := time.Now().Add(debounce)
+
+	for update := range progressChan {
+		if update.Error != nil {
+			logger.Error("push update", zap.Error(update.Error))
+			errChan <- update.Error
+			break
+		}
+
+		if update.Complete == update.Total {
+			logger.Info("pushed", zap.Int64("completed", update.Complete), zap.Int64("total", update.Total))
+		} else if time.Now().After(nextProgress) {
+			nextProgress = time.Now().Add(debounce)
+			logger.Info("push", zap.Int64("completed", update.Complete), zap.Int64("total", update.Total))
+		}
+	}
+
+	return <-errChan
+}
@@ -0,0 +1,32 @@
+package appender
+
+import (
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/daemon"
+	"go.uber.org/zap"
+)
+
+// daemonOutput side-loads the result image into a local Docker daemon, for dev
+// loops that want to run the result without a registry round-trip. daemon.Write
+// requires a tag, not just any reference, so ref is a name.Tag rather than the
+// broader name.Reference used elsewhere in this package.
+type daemonOutput struct {
+	ref name.Tag
+}
+
+func (o *daemonOutput) Push(image Pushable) (v1.Hash, error) {
+	img, ok := image.(v1.Image)
+	if !ok {
+		return v1.Hash{}, fmt.Errorf("daemon output: image indexes are not supported, got %T", image)
+	}
+	zap.L().Info("loading into daemon", zap.String("ref", o.ref.String()))
+	response, err := daemon.Write(o.ref, img)
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("loading %s into daemon: %w", o.ref.String(), err)
+	}
+	zap.L().Debug("daemon load response", zap.String("response", response))
+	return img.Digest()
+}
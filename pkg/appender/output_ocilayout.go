@@ -0,0 +1,44 @@
+package appender
+
+import (
+	"fmt"
+	"os"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"go.uber.org/zap"
+)
+
+// ociLayoutOutput appends the result into an OCI image layout directory on disk,
+// creating it on first write so repeated invocations accumulate into one
+// distributable artifact.
+type ociLayoutOutput struct {
+	path string
+}
+
+func (o *ociLayoutOutput) Push(image Pushable) (v1.Hash, error) {
+	p, err := layout.FromPath(o.path)
+	if os.IsNotExist(err) {
+		zap.L().Debug("creating OCI layout", zap.String("path", o.path))
+		p, err = layout.Write(o.path, empty.Index)
+	}
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("opening OCI layout %s: %w", o.path, err)
+	}
+
+	switch v := image.(type) {
+	case v1.Image:
+		err = p.AppendImage(v)
+	case v1.ImageIndex:
+		err = p.AppendIndex(v)
+	default:
+		err = fmt.Errorf("OCI layout output: unsupported pushable type %T", image)
+	}
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("appending to OCI layout %s: %w", o.path, err)
+	}
+
+	zap.L().Info("appended to OCI layout", zap.String("path", o.path))
+	return image.Digest()
+}
@@ -0,0 +1,46 @@
+package appender
+
+import (
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"go.uber.org/zap"
+)
+
+// registryOutput pushes to a remote registry, the default output when
+// ContainConfig.Tag has no recognized scheme prefix.
+type registryOutput struct {
+	ref          name.Reference
+	craneOptions crane.Options
+}
+
+func (o *registryOutput) Push(image Pushable) (v1.Hash, error) {
+	mediaType, err := image.MediaType()
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	zap.L().Info("pushing", zap.String("ref", o.ref.String()), zap.String("mediaType", string(mediaType)))
+
+	progressChan := make(chan v1.Update, 200)
+	errChan := make(chan error, 2)
+	options := append(o.craneOptions.Remote, remote.WithProgress(progressChan))
+
+	go func() {
+		switch v := image.(type) {
+		case v1.Image:
+			errChan <- remote.Write(o.ref, v, options...)
+		case v1.ImageIndex:
+			errChan <- remote.WriteIndex(o.ref, v, options...)
+		default:
+			errChan <- fmt.Errorf("registry output: unsupported pushable type %T", image)
+		}
+	}()
+
+	if err := reportProgress(progressChan, errChan); err != nil {
+		return v1.Hash{}, err
+	}
+	return image.Digest()
+}
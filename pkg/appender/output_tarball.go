@@ -0,0 +1,56 @@
+package appender
+
+import (
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"go.uber.org/zap"
+)
+
+// tarballOutput writes the result as a single tarball on disk, for air-gapped
+// delivery without a registry or daemon.
+type tarballOutput struct {
+	path string
+}
+
+func (o *tarballOutput) Push(image Pushable) (v1.Hash, error) {
+	zap.L().Info("writing tarball", zap.String("path", o.path))
+
+	switch v := image.(type) {
+	case v1.Image:
+		// nil tag: the tarball's manifest.json carries no RepoTags, since the
+		// destination path isn't an image reference.
+		if err := tarball.WriteToFile(o.path, nil, v); err != nil {
+			return v1.Hash{}, fmt.Errorf("writing tarball %s: %w", o.path, err)
+		}
+	case v1.ImageIndex:
+		manifest, err := v.IndexManifest()
+		if err != nil {
+			return v1.Hash{}, fmt.Errorf("reading index manifest for tarball %s: %w", o.path, err)
+		}
+		refToImage := map[name.Reference]v1.Image{}
+		for _, desc := range manifest.Manifests {
+			img, err := v.Image(desc.Digest)
+			if err != nil {
+				return v1.Hash{}, fmt.Errorf("reading platform image %s for tarball %s: %w", desc.Digest, o.path, err)
+			}
+			// A digest reference, not a tag: there's no meaningful repo name to
+			// give each platform image, so use a placeholder repo with the real
+			// digest pinned.
+			ref, err := name.ParseReference(fmt.Sprintf("image@%s", desc.Digest))
+			if err != nil {
+				return v1.Hash{}, fmt.Errorf("building reference for %s in tarball %s: %w", desc.Digest, o.path, err)
+			}
+			refToImage[ref] = img
+		}
+		if err := tarball.MultiRefWriteToFile(o.path, refToImage); err != nil {
+			return v1.Hash{}, fmt.Errorf("writing multi-platform tarball %s: %w", o.path, err)
+		}
+	default:
+		return v1.Hash{}, fmt.Errorf("tarball output: unsupported pushable type %T", image)
+	}
+
+	return image.Digest()
+}
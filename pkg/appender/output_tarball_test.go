@@ -0,0 +1,26 @@
+package appender
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+func TestTarballPlatformReferenceIsNotTheRawPlatformString(t *testing.T) {
+	digest := v1.Hash{Algorithm: "sha256", Hex: testDigestHex}
+
+	ref, err := name.ParseReference(fmt.Sprintf("image@%s", digest))
+	if err != nil {
+		t.Fatalf("building placeholder reference: %v", err)
+	}
+
+	// Regression guard: an earlier version keyed crane.MultiSave's map with
+	// platform strings like "linux/amd64", which name.ParseReference also
+	// happens to accept but resolves to a bogus repo tag. Make sure our digest
+	// reference round-trips to the digest we asked for.
+	if ref.Identifier() != digest.String() {
+		t.Errorf("reference identifier = %q, want %q", ref.Identifier(), digest.String())
+	}
+}
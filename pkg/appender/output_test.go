@@ -0,0 +1,29 @@
+package appender
+
+import "testing"
+
+func TestSplitOutputScheme(t *testing.T) {
+	cases := []struct {
+		tag        string
+		wantScheme string
+		wantRest   string
+	}{
+		{"daemon://myapp:dev", outputSchemeDaemon, "myapp:dev"},
+		{"oci-layout:./out", outputSchemeOCILayout, "./out"},
+		{"tarball:./img.tar", outputSchemeTarball, "./img.tar"},
+		{"registry.example.com/myapp:dev", "", "registry.example.com/myapp:dev"},
+	}
+	for _, c := range cases {
+		scheme, rest := splitOutputScheme(c.tag)
+		if scheme != c.wantScheme || rest != c.wantRest {
+			t.Errorf("splitOutputScheme(%q) = (%q, %q), want (%q, %q)", c.tag, scheme, rest, c.wantScheme, c.wantRest)
+		}
+	}
+}
+
+func TestNewDaemonOutputRequiresTag(t *testing.T) {
+	_, err := New(testConfig("busybox", "daemon://myapp@sha256:"+testDigestHex))
+	if err == nil {
+		t.Fatal("expected an error for a digest-only daemon tag, got nil")
+	}
+}
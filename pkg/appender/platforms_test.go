@@ -0,0 +1,58 @@
+package appender
+
+import (
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+func TestParsePlatforms(t *testing.T) {
+	got, err := parsePlatforms([]string{"linux/amd64", "linux/arm64/v8"})
+	if err != nil {
+		t.Fatalf("parsePlatforms: %v", err)
+	}
+	want := []v1.Platform{
+		{OS: "linux", Architecture: "amd64"},
+		{OS: "linux", Architecture: "arm64", Variant: "v8"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parsePlatforms returned %d platforms, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].OS != want[i].OS || got[i].Architecture != want[i].Architecture || got[i].Variant != want[i].Variant {
+			t.Errorf("platform %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParsePlatformsInvalid(t *testing.T) {
+	if _, err := parsePlatforms([]string{"not-a-platform!!"}); err == nil {
+		t.Error("parsePlatforms with a malformed entry returned nil error, want one")
+	}
+}
+
+func TestPlatformWanted(t *testing.T) {
+	wanted, err := parsePlatforms([]string{"linux/amd64", "linux/arm64/v8"})
+	if err != nil {
+		t.Fatalf("parsePlatforms: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		platform v1.Platform
+		want     bool
+	}{
+		{name: "exact match no variant", platform: v1.Platform{OS: "linux", Architecture: "amd64"}, want: true},
+		{name: "matching variant", platform: v1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}, want: true},
+		{name: "mismatched variant", platform: v1.Platform{OS: "linux", Architecture: "arm64", Variant: "v7"}, want: false},
+		{name: "different arch", platform: v1.Platform{OS: "linux", Architecture: "386"}, want: false},
+		{name: "different os", platform: v1.Platform{OS: "windows", Architecture: "amd64"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := platformWanted(tt.platform, wanted); got != tt.want {
+				t.Errorf("platformWanted(%+v) = %v, want %v", tt.platform, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package appender
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	schema "github.com/turbokube/contain/pkg/schema/v1"
+	"go.uber.org/zap"
+)
+
+// epoch is the timestamp used by schema.ReproducibleZero, equivalent to
+// SOURCE_DATE_EPOCH=0.
+var epoch = time.Unix(0, 0).UTC()
+
+// TimestampedLayer lets a layer producer (for example a LocalDir builder) report
+// the newest mtime it observed while building a layer, so that
+// schema.ReproducibleSourceTimestamp can reuse it without Appender having to
+// re-read the tar stream.
+type TimestampedLayer struct {
+	v1.Layer
+	SourceTimestamp time.Time
+}
+
+// createdTime resolves the timestamp that Reproducible should stamp onto the
+// result image config and, absent a more specific per-layer TimestampedLayer,
+// onto appended layers. buildTimestamp is the time.Now() captured once at the
+// start of Append; layers are the (not yet normalized) layers passed to Append,
+// inspected here for ReproducibleSourceTimestamp.
+func (c *Appender) createdTime(buildTimestamp time.Time, layers []v1.Layer) time.Time {
+	switch c.config.Reproducible.Mode {
+	case schema.ReproducibleZero:
+		return epoch
+	case schema.ReproducibleSourceTimestamp:
+		if c.config.Reproducible.SourceDateEpoch != nil {
+			return time.Unix(*c.config.Reproducible.SourceDateEpoch, 0).UTC()
+		}
+		if observed, ok := maxSourceTimestamp(layers); ok {
+			return observed
+		}
+		zap.L().Warn("reproducible SourceTimestamp mode selected but no layer reported a source timestamp (wrap it in appender.TimestampedLayer), falling back to build time which will differ on every run")
+		return buildTimestamp
+	case schema.ReproducibleBuildTimestamp:
+		return buildTimestamp
+	default:
+		return time.Time{}
+	}
+}
+
+// maxSourceTimestamp returns the newest TimestampedLayer.SourceTimestamp among
+// layers, and whether any layer reported one at all.
+func maxSourceTimestamp(layers []v1.Layer) (time.Time, bool) {
+	var max time.Time
+	found := false
+	for _, layer := range layers {
+		t, ok := layer.(TimestampedLayer)
+		if !ok || t.SourceTimestamp.IsZero() {
+			continue
+		}
+		found = true
+		if t.SourceTimestamp.After(max) {
+			max = t.SourceTimestamp
+		}
+	}
+	return max, found
+}
+
+// normalizeLayers rewrites layers' tar streams so their ModTime/AccessTime/ChangeTime
+// are deterministic. Layers are returned unchanged if Reproducible.Mode is unset.
+func (c *Appender) normalizeLayers(layers []v1.Layer, created time.Time) ([]v1.Layer, error) {
+	if c.config.Reproducible.Mode == "" {
+		return layers, nil
+	}
+	normalized := make([]v1.Layer, len(layers))
+	for i, layer := range layers {
+		layerCreated := created
+		if c.config.Reproducible.Mode == schema.ReproducibleSourceTimestamp && c.config.Reproducible.SourceDateEpoch == nil {
+			if t, ok := layer.(TimestampedLayer); ok && !t.SourceTimestamp.IsZero() {
+				layerCreated = t.SourceTimestamp
+			}
+		}
+		out, err := normalizeLayer(layer, layerCreated)
+		if err != nil {
+			return nil, fmt.Errorf("normalizing layer for reproducible build: %w", err)
+		}
+		normalized[i] = out
+	}
+	return normalized, nil
+}
+
+// normalizeLayer rebuilds a layer's tar stream with sorted entries and cleared
+// uid/gid names and xattrs, and stamps every header with created.
+func normalizeLayer(layer v1.Layer, created time.Time) (v1.Layer, error) {
+	rc, err := layer.Uncompressed()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	type entry struct {
+		header *tar.Header
+		body   []byte
+	}
+	var entries []entry
+	tr := tar.NewReader(rc)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		header.ModTime = created
+		header.AccessTime = created
+		header.ChangeTime = created
+		header.Uname = ""
+		header.Gname = ""
+		header.PAXRecords = nil
+		header.Xattrs = nil
+		entries = append(entries, entry{header: header, body: body})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].header.Name < entries[j].header.Name })
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		if err := tw.WriteHeader(e.header); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(e.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	raw := buf.Bytes()
+
+	return tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(raw)), nil
+	})
+}
+
+// withCreated stamps the image config Created timestamp when Reproducible is
+// configured, leaving img untouched otherwise.
+func (c *Appender) withCreated(img v1.Image, created time.Time) (v1.Image, error) {
+	if c.config.Reproducible.Mode == "" {
+		return img, nil
+	}
+	return mutate.CreatedAt(img, v1.Time{Time: created})
+}
+
+// warnIfBaseNotReproducible logs when the base image's own Created timestamp is
+// nonzero, since those layers stay outside Appender's control.
+func warnIfBaseNotReproducible(base v1.Image) {
+	cfg, err := base.ConfigFile()
+	if err != nil {
+		return
+	}
+	if !cfg.Created.Time.IsZero() && !cfg.Created.Time.Equal(epoch) {
+		zap.L().Warn("base image is not reproducible, its layers may vary between builds",
+			zap.Time("created", cfg.Created.Time),
+		)
+	}
+}
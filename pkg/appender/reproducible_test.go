@@ -0,0 +1,69 @@
+package appender
+
+import (
+	"testing"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	schema "github.com/turbokube/contain/pkg/schema/v1"
+)
+
+func TestCreatedTimeSourceTimestampUsesObservedMax(t *testing.T) {
+	c := &Appender{config: schema.ContainConfig{
+		Reproducible: schema.Reproducible{Mode: schema.ReproducibleSourceTimestamp},
+	}}
+
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	layers := []v1.Layer{
+		TimestampedLayer{Layer: emptyLayer(t), SourceTimestamp: older},
+		TimestampedLayer{Layer: emptyLayer(t), SourceTimestamp: newer},
+	}
+
+	buildTimestamp := time.Now()
+	got := c.createdTime(buildTimestamp, layers)
+	if !got.Equal(newer) {
+		t.Errorf("createdTime = %v, want the newest observed source timestamp %v (not build time %v)", got, newer, buildTimestamp)
+	}
+}
+
+func TestCreatedTimeSourceTimestampFallsBackWithoutObservation(t *testing.T) {
+	c := &Appender{config: schema.ContainConfig{
+		Reproducible: schema.Reproducible{Mode: schema.ReproducibleSourceTimestamp},
+	}}
+
+	buildTimestamp := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	got := c.createdTime(buildTimestamp, []v1.Layer{emptyLayer(t)})
+	if !got.Equal(buildTimestamp) {
+		t.Errorf("createdTime = %v, want buildTimestamp %v when no layer reports a source timestamp", got, buildTimestamp)
+	}
+}
+
+func TestCreatedTimeSourceDateEpochOverridesObservation(t *testing.T) {
+	epochSeconds := int64(1000000000)
+	c := &Appender{config: schema.ContainConfig{
+		Reproducible: schema.Reproducible{
+			Mode:            schema.ReproducibleSourceTimestamp,
+			SourceDateEpoch: &epochSeconds,
+		},
+	}}
+
+	layers := []v1.Layer{TimestampedLayer{Layer: emptyLayer(t), SourceTimestamp: time.Now()}}
+	got := c.createdTime(time.Now(), layers)
+	want := time.Unix(epochSeconds, 0).UTC()
+	if !got.Equal(want) {
+		t.Errorf("createdTime = %v, want SourceDateEpoch %v", got, want)
+	}
+}
+
+// emptyLayer returns a placeholder v1.Layer for tests that only exercise
+// TimestampedLayer's own SourceTimestamp field, never the wrapped layer's
+// content.
+func emptyLayer(t *testing.T) v1.Layer {
+	t.Helper()
+	return stubLayer{}
+}
+
+// stubLayer is a minimal v1.Layer for tests that only need a non-nil value to
+// wrap in TimestampedLayer.
+type stubLayer struct{ v1.Layer }
@@ -0,0 +1,260 @@
+// Package basecache provides an on-disk, content-addressed cache for base
+// images so Appender doesn't have to re-pull them on every invocation. Entries
+// are keyed by the resolved image digest, so a tag-based base reference is
+// pinned to one digest per run regardless of cache state.
+package basecache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/match"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	schema "github.com/turbokube/contain/pkg/schema/v1"
+	"go.uber.org/zap"
+)
+
+// Cache wraps an OCI layout directory used as a content-addressed cache for
+// base images.
+type Cache struct {
+	path         string
+	maxSize      int64
+	craneOptions crane.Options
+}
+
+// New opens the OCI layout at config.Path, creating it if it doesn't exist yet.
+func New(config schema.Cache, craneOptions crane.Options) (*Cache, error) {
+	maxSize, err := parseSize(config.MaxSize)
+	if err != nil {
+		return nil, fmt.Errorf("parsing cache maxSize %q: %w", config.MaxSize, err)
+	}
+	if _, err := layout.FromPath(config.Path); err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("opening cache layout %s: %w", config.Path, err)
+		}
+		if _, err := layout.Write(config.Path, empty.Index); err != nil {
+			return nil, fmt.Errorf("creating cache layout %s: %w", config.Path, err)
+		}
+	}
+	return &Cache{path: config.Path, maxSize: maxSize, craneOptions: craneOptions}, nil
+}
+
+// Get resolves ref to a digest and returns the corresponding image, either from
+// the on-disk cache or by pulling and caching it on miss. The resolved digest is
+// returned alongside the image so callers can pin and report exactly which base
+// was used.
+func (c *Cache) Get(ref name.Reference) (v1.Image, v1.Hash, error) {
+	desc, err := remote.Get(ref, c.craneOptions.Remote...)
+	if err != nil {
+		return nil, v1.Hash{}, fmt.Errorf("resolving %s: %w", ref.String(), err)
+	}
+	digest := desc.Digest
+
+	if idx, err := layout.ImageIndexFromPath(c.path); err == nil {
+		if img, err := idx.Image(digest); err == nil {
+			zap.L().Debug("base cache hit", zap.String("ref", ref.String()), zap.String("digest", digest.String()))
+			c.touch(digest)
+			return img, digest, nil
+		}
+	}
+
+	zap.L().Debug("base cache miss", zap.String("ref", ref.String()), zap.String("digest", digest.String()))
+	img, err := remote.Image(ref, c.craneOptions.Remote...)
+	if err != nil {
+		return nil, digest, fmt.Errorf("pulling %s: %w", ref.String(), err)
+	}
+	path, err := layout.FromPath(c.path)
+	if err != nil {
+		return nil, digest, fmt.Errorf("opening cache layout %s: %w", c.path, err)
+	}
+	if err := path.AppendImage(img); err != nil {
+		return nil, digest, fmt.Errorf("writing %s into cache: %w", digest.String(), err)
+	}
+	if err := c.evict(path); err != nil {
+		zap.L().Warn("base cache eviction failed", zap.Error(err))
+	}
+	return img, digest, nil
+}
+
+// touch bumps a cached manifest's mtime so evict's LRU ordering reflects use,
+// not just insertion order.
+func (c *Cache) touch(digest v1.Hash) {
+	blob := filepath.Join(c.path, "blobs", digest.Algorithm, digest.Hex)
+	now := time.Now()
+	if err := os.Chtimes(blob, now, now); err != nil {
+		zap.L().Debug("touching base cache entry", zap.Error(err))
+	}
+}
+
+// evict removes the least-recently-used images from path until the cache is at
+// or under maxSize, then garbage-collects the blobs that removal orphaned. A
+// zero maxSize means unbounded, so evict is a no-op.
+func (c *Cache) evict(path layout.Path) error {
+	if c.maxSize <= 0 {
+		return nil
+	}
+	idx, err := path.ImageIndex()
+	if err != nil {
+		return err
+	}
+	manifest, err := idx.IndexManifest()
+	if err != nil {
+		return err
+	}
+
+	type entry struct {
+		digest v1.Hash
+		size   int64
+		atime  time.Time
+	}
+	entries := make([]entry, 0, len(manifest.Manifests))
+	var total int64
+	for _, desc := range manifest.Manifests {
+		img, err := idx.Image(desc.Digest)
+		if err != nil {
+			continue
+		}
+		// desc.Size is the size of the manifest blob itself, a few hundred
+		// bytes; what actually takes up disk space is the config and layers it
+		// references.
+		size, err := imageDiskSize(img)
+		if err != nil {
+			continue
+		}
+		blob := filepath.Join(c.path, "blobs", desc.Digest.Algorithm, desc.Digest.Hex)
+		info, err := os.Stat(blob)
+		if err != nil {
+			continue
+		}
+		total += size
+		entries = append(entries, entry{digest: desc.Digest, size: size, atime: info.ModTime()})
+	}
+	if total <= c.maxSize {
+		return nil
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].atime.Before(entries[j].atime) })
+	for _, e := range entries {
+		if total <= c.maxSize {
+			break
+		}
+		if err := path.RemoveDescriptors(match.Digests(e.digest)); err != nil {
+			return fmt.Errorf("evicting %s from cache: %w", e.digest.String(), err)
+		}
+		total -= e.size
+		zap.L().Info("evicted base image from cache", zap.String("digest", e.digest.String()), zap.Int64("bytes", e.size))
+	}
+
+	return c.gc(path)
+}
+
+// imageDiskSize sums the sizes of img's config and layer blobs: the parts of a
+// cached image that actually occupy disk space, unlike the tiny manifest JSON
+// that indexes them.
+func imageDiskSize(img v1.Image) (int64, error) {
+	manifest, err := img.Manifest()
+	if err != nil {
+		return 0, err
+	}
+	total := manifest.Config.Size
+	for _, l := range manifest.Layers {
+		total += l.Size
+	}
+	return total, nil
+}
+
+// gc removes blob files under path that are no longer referenced by any
+// manifest still in the index, reclaiming the disk space that
+// path.RemoveDescriptors alone leaves behind.
+func (c *Cache) gc(path layout.Path) error {
+	idx, err := path.ImageIndex()
+	if err != nil {
+		return err
+	}
+	manifest, err := idx.IndexManifest()
+	if err != nil {
+		return err
+	}
+
+	referenced := map[string]bool{}
+	for _, desc := range manifest.Manifests {
+		referenced[desc.Digest.String()] = true
+		img, err := idx.Image(desc.Digest)
+		if err != nil {
+			continue
+		}
+		imgManifest, err := img.Manifest()
+		if err != nil {
+			continue
+		}
+		referenced[imgManifest.Config.Digest.String()] = true
+		for _, l := range imgManifest.Layers {
+			referenced[l.Digest.String()] = true
+		}
+	}
+
+	algoDirs, err := os.ReadDir(filepath.Join(c.path, "blobs"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, algoDir := range algoDirs {
+		if !algoDir.IsDir() {
+			continue
+		}
+		dir := filepath.Join(c.path, "blobs", algoDir.Name())
+		blobs, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, blob := range blobs {
+			key := algoDir.Name() + ":" + blob.Name()
+			if referenced[key] {
+				continue
+			}
+			if err := os.Remove(filepath.Join(dir, blob.Name())); err != nil {
+				zap.L().Warn("base cache gc: failed to remove unreferenced blob", zap.String("blob", key), zap.Error(err))
+				continue
+			}
+			zap.L().Debug("base cache gc: removed unreferenced blob", zap.String("blob", key))
+		}
+	}
+	return nil
+}
+
+// parseSize parses a human size such as "10Gi" or "500Mi" into bytes. An empty
+// string means unbounded (0).
+func parseSize(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	units := []struct {
+		suffix string
+		factor int64
+	}{
+		{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30}, {"Ti", 1 << 40},
+		{"K", 1000}, {"M", 1000 * 1000}, {"G", 1000 * 1000 * 1000},
+	}
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			n, err := strconv.ParseInt(strings.TrimSuffix(s, u.suffix), 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return n * u.factor, nil
+		}
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
@@ -0,0 +1,136 @@
+package basecache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "", want: 0},
+		{in: "1000", want: 1000},
+		{in: "1K", want: 1000},
+		{in: "1Ki", want: 1 << 10},
+		{in: "10Gi", want: 10 * (1 << 30)},
+		{in: "500Mi", want: 500 * (1 << 20)},
+		{in: "not-a-size", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseSize(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseSize(%q) = %d, nil, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseSize(%q) returned error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestImageDiskSizeMatchesConfigAndLayers(t *testing.T) {
+	img, err := random.Image(1024, 3)
+	if err != nil {
+		t.Fatalf("building random image: %v", err)
+	}
+	manifest, err := img.Manifest()
+	if err != nil {
+		t.Fatalf("reading manifest: %v", err)
+	}
+	want := manifest.Config.Size
+	for _, l := range manifest.Layers {
+		want += l.Size
+	}
+
+	got, err := imageDiskSize(img)
+	if err != nil {
+		t.Fatalf("imageDiskSize: %v", err)
+	}
+	if got != want {
+		t.Errorf("imageDiskSize = %d, want %d (config + layers, not the manifest blob itself)", got, want)
+	}
+}
+
+func TestEvictRemovesOldestAndGCsOrphanedBlobs(t *testing.T) {
+	dir := t.TempDir()
+	path, err := layout.Write(dir, empty.Index)
+	if err != nil {
+		t.Fatalf("creating layout: %v", err)
+	}
+
+	older, err := random.Image(2048, 1)
+	if err != nil {
+		t.Fatalf("building older image: %v", err)
+	}
+	newer, err := random.Image(2048, 1)
+	if err != nil {
+		t.Fatalf("building newer image: %v", err)
+	}
+	if err := path.AppendImage(older); err != nil {
+		t.Fatalf("appending older image: %v", err)
+	}
+	olderDigest, err := older.Digest()
+	if err != nil {
+		t.Fatalf("older.Digest: %v", err)
+	}
+	// evict orders by blob mtime, so back-date the first entry relative to the
+	// second the way a real cache accrues use over time.
+	olderBlob := filepath.Join(dir, "blobs", olderDigest.Algorithm, olderDigest.Hex)
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(olderBlob, past, past); err != nil {
+		t.Fatalf("backdating older blob: %v", err)
+	}
+
+	if err := path.AppendImage(newer); err != nil {
+		t.Fatalf("appending newer image: %v", err)
+	}
+	newerSize, err := imageDiskSize(newer)
+	if err != nil {
+		t.Fatalf("imageDiskSize(newer): %v", err)
+	}
+
+	// Cap the cache below the combined size so only the newer image survives.
+	c := &Cache{path: dir, maxSize: newerSize + newerSize/2}
+	if err := c.evict(path); err != nil {
+		t.Fatalf("evict: %v", err)
+	}
+
+	idx, err := path.ImageIndex()
+	if err != nil {
+		t.Fatalf("reading index after evict: %v", err)
+	}
+	manifest, err := idx.IndexManifest()
+	if err != nil {
+		t.Fatalf("reading index manifest after evict: %v", err)
+	}
+	if len(manifest.Manifests) != 1 {
+		t.Fatalf("index has %d manifests after evict, want 1", len(manifest.Manifests))
+	}
+	newerDigest, err := newer.Digest()
+	if err != nil {
+		t.Fatalf("newer.Digest: %v", err)
+	}
+	if manifest.Manifests[0].Digest != newerDigest {
+		t.Errorf("surviving manifest = %s, want the newer (more recently touched) image %s", manifest.Manifests[0].Digest, newerDigest)
+	}
+
+	// The older image's now-unreferenced blobs should have been GC'd.
+	if _, err := os.Stat(olderBlob); !os.IsNotExist(err) {
+		t.Errorf("older image's manifest blob still exists after evict, want it GC'd: err=%v", err)
+	}
+}
@@ -6,11 +6,79 @@ type ContainConfig struct {
 	Status ContainConfigStatus
 	// Base is the base image reference
 	Base string `yaml:"base"`
-	// Tag is the result reference to be pushed
-	Tag       string   `yaml:"tag"`
-	Platforms []string `yaml:"platforms"`
-	Layers    []Layer  `yaml:"layers,omitempty"`
-	Sync      ContainConfigSync
+	// Tag is the result reference to be pushed. A scheme prefix selects an
+	// alternate output: "daemon://" loads into a local Docker daemon,
+	// "oci-layout:" appends into an OCI layout directory, "tarball:" writes a
+	// tarball; with no recognized prefix Tag is pushed to a registry.
+	Tag          string       `yaml:"tag"`
+	Platforms    []string     `yaml:"platforms"`
+	Layers       []Layer      `yaml:"layers,omitempty"`
+	Reproducible Reproducible `yaml:"reproducible,omitempty"`
+	ImageConfig  ImageConfig  `yaml:"imageConfig,omitempty"`
+	Cache        Cache        `yaml:"cache,omitempty"`
+	Sync         ContainConfigSync
+}
+
+// Cache configures an on-disk OCI layout used to avoid re-pulling the base image
+// on every invocation, see pkg/basecache. Leaving Path unset disables the cache.
+type Cache struct {
+	Path string `yaml:"path,omitempty"`
+	// MaxSize bounds the cache's on-disk size, for example "10Gi". Entries are
+	// evicted least-recently-used first. Unset or "0" means unbounded.
+	MaxSize string `yaml:"maxSize,omitempty"`
+}
+
+// ImageConfig mutates the result image's config alongside the appended layers,
+// so contain can produce a final runnable image without a separate Dockerfile
+// step. List and map fields are merged into the base image's config by default;
+// set the matching Replace flag to fully override the base instead.
+type ImageConfig struct {
+	Entrypoint   []string           `yaml:"entrypoint,omitempty"`
+	Cmd          []string           `yaml:"cmd,omitempty"`
+	Env          []string           `yaml:"env,omitempty"`
+	WorkingDir   string             `yaml:"workingDir,omitempty"`
+	User         string             `yaml:"user,omitempty"`
+	Labels       map[string]string  `yaml:"labels,omitempty"`
+	ExposedPorts []string           `yaml:"exposedPorts,omitempty"`
+	StopSignal   string             `yaml:"stopSignal,omitempty"`
+	Volumes      []string           `yaml:"volumes,omitempty"`
+	Replace      ImageConfigReplace `yaml:"replace,omitempty"`
+}
+
+// ImageConfigReplace selects, per field group, whether ImageConfig fully
+// overrides the base image's config instead of merging into it. Scalar fields
+// (WorkingDir, User, StopSignal) and Labels have no replace flag: a non-empty
+// scalar always overrides, and Labels are always merged.
+type ImageConfigReplace struct {
+	Entrypoint   bool `yaml:"entrypoint,omitempty"`
+	Cmd          bool `yaml:"cmd,omitempty"`
+	Env          bool `yaml:"env,omitempty"`
+	ExposedPorts bool `yaml:"exposedPorts,omitempty"`
+	Volumes      bool `yaml:"volumes,omitempty"`
+}
+
+// ReproducibleMode selects how Appender timestamps the result image config and
+// appended layers so that repeated builds produce byte-identical output.
+type ReproducibleMode string
+
+const (
+	// ReproducibleZero sets all timestamps to the Unix epoch, equivalent to
+	// SOURCE_DATE_EPOCH=0.
+	ReproducibleZero ReproducibleMode = "Zero"
+	// ReproducibleSourceTimestamp uses the newest mtime observed while building
+	// the appended layers, or SourceDateEpoch if set.
+	ReproducibleSourceTimestamp ReproducibleMode = "SourceTimestamp"
+	// ReproducibleBuildTimestamp uses the time Append was invoked.
+	ReproducibleBuildTimestamp ReproducibleMode = "BuildTimestamp"
+)
+
+// Reproducible controls timestamp normalization for byte-identical builds across
+// runs. Leaving Mode unset keeps the current non-reproducible behavior.
+type Reproducible struct {
+	Mode ReproducibleMode `yaml:"mode,omitempty"`
+	// SourceDateEpoch overrides the timestamp used by ReproducibleSourceTimestamp,
+	// see https://reproducible-builds.org/docs/source-date-epoch/
+	SourceDateEpoch *int64 `yaml:"sourceDateEpoch,omitempty"`
 }
 
 type ContainConfigStatus struct {
@@ -18,6 +86,10 @@ type ContainConfigStatus struct {
 	Md5       string // config source md5 (not for template)
 	Sha256    string // config source sha256 (not for template)
 	Overrides ContainConfigOverrides
+	// ResolvedBaseDigest is the digest Base was pinned to for this run, set when
+	// Cache is configured or when Base is an image index (then the index digest),
+	// so downstream tooling can see exactly which base was used.
+	ResolvedBaseDigest string
 }
 
 type ContainConfigOverrides struct {